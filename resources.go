@@ -3,16 +3,27 @@ package resources
 
 import (
 	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"go/format"
 	"io"
+	"io/fs"
 	"log"
 	"os"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
 	"text/template"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
 )
 
-// File mimicks the os.File and http.File interface.
+// File mimicks the os.File and fs.File interface. Any fs.File (for example
+// one obtained from an embed.FS) satisfies this interface as-is.
 type File interface {
 	io.Reader
 	Stat() (os.FileInfo, error)
@@ -32,11 +43,51 @@ func New() *Package {
 
 // Config defines some details about the output file
 type Config struct {
-	Pkg     string // Pkg holds the package name
-	Var     string // Var holds the variable name for the virtual filesystem
-	Tag     string // Tag may hold an optional build tag, unless empty
-	Declare bool   // Declare controls if the Var should be declared as well
-	Format  bool   // Format controls, whether gofmt should be applied to the output
+	Pkg         string      // Pkg holds the package name
+	Var         string      // Var holds the variable name for the virtual filesystem
+	Tag         string      // Tag may hold an optional build tag, unless empty
+	Declare     bool        // Declare controls if the Var should be declared as well
+	Format      bool        // Format controls, whether gofmt should be applied to the output
+	Compression Compression // Compression controls how embedded file bytes are encoded
+
+	// SourceDateEpoch overrides the modTime embedded for every file, so Build
+	// produces byte-for-byte reproducible output across machines and runs
+	// instead of baking in each file's real, non-deterministic mtime. When
+	// zero, it falls back to the SOURCE_DATE_EPOCH environment variable
+	// (https://reproducible-builds.org/specs/source-date-epoch/), and then
+	// to the Unix epoch.
+	SourceDateEpoch time.Time
+}
+
+// Compression selects how a file's bytes are encoded in the generated
+// source. Compressed bytes are decompressed at most once per process, the
+// first time the file is opened.
+type Compression int
+
+// Supported compression schemes.
+const (
+	NoCompression Compression = iota
+	Gzip
+	Zstd
+)
+
+// IsGzip reports whether c selects gzip compression.
+func (c Compression) IsGzip() bool { return c == Gzip }
+
+// IsZstd reports whether c selects zstd compression.
+func (c Compression) IsZstd() bool { return c == Zstd }
+
+// GoString renders c as the identifier of the matching constant in the
+// generated package.
+func (c Compression) GoString() string {
+	switch c {
+	case Gzip:
+		return "Gzip"
+	case Zstd:
+		return "Zstd"
+	default:
+		return "NoCompression"
+	}
 }
 
 // A Package describes a collection of files and how they should be tranformed
@@ -44,6 +95,21 @@ type Config struct {
 type Package struct {
 	Config
 	Files map[string]File
+
+	transforms []Transformer
+}
+
+// Transformer processes a file's bytes during Build, before they are
+// embedded. Transformers run in registration order, each receiving the
+// previous one's output as its input.
+type Transformer func(path string, in io.Reader) (io.Reader, error)
+
+// Use registers transform to run over every file in the package during
+// Build. This turns Package into an asset pipeline: ship the built-in
+// Minify, CompressGzip, CompressBrotli and RenderTemplate transforms, or
+// supply a custom one.
+func (p *Package) Use(transform Transformer) {
+	p.transforms = append(p.transforms, transform)
 }
 
 // Add a file to the package at the give path.
@@ -62,9 +128,109 @@ func (p *Package) AddFile(path string, file string) error {
 	return p.Add(path, f)
 }
 
+// bytesFile adapts an in-memory byte slice to the File interface, so
+// transforms can synthesize entries (transformed content, Content-Encoding
+// siblings, ...) without touching disk.
+type bytesFile struct {
+	*bytes.Reader
+	name    string
+	modTime time.Time
+}
+
+func newBytesFile(name string, data []byte, modTime time.Time) *bytesFile {
+	return &bytesFile{Reader: bytes.NewReader(data), name: name, modTime: modTime}
+}
+
+// Stat implements File.
+func (f *bytesFile) Stat() (os.FileInfo, error) {
+	return &bytesFileInfo{name: f.name, size: f.Reader.Size(), modTime: f.modTime}, nil
+}
+
+type bytesFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (fi *bytesFileInfo) Name() string       { return fi.name }
+func (fi *bytesFileInfo) Size() int64        { return fi.size }
+func (fi *bytesFileInfo) Mode() os.FileMode  { return 0 }
+func (fi *bytesFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *bytesFileInfo) IsDir() bool        { return false }
+func (fi *bytesFileInfo) Sys() interface{}   { return nil }
+
+// AddFS walks fsys and adds every non-directory entry it contains to the package,
+// rooted at prefix. It lets a Package be populated straight from an
+// fs.FS, such as an embed.FS built with a //go:embed directive, instead of
+// hand-calling AddFile for every path.
+func (p *Package) AddFS(prefix string, fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		f, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		return p.Add(path.Join(prefix, name), f)
+	})
+}
+
 // Build compiles the package and writes it into an io.Writer.
 func (p *Package) Build(out io.Writer) error {
-	return pkg.Execute(out, p)
+	if err := p.runTransforms(); err != nil {
+		return err
+	}
+
+	data, err := newBuildData(p)
+	if err != nil {
+		return err
+	}
+	return pkg.Execute(out, data)
+}
+
+// runTransforms pipes every file already in p.Files through the registered
+// Transformers, in order, and replaces each entry with the result. It runs
+// once, against the files present at the time Build is called, so a
+// Transformer that adds sibling entries (CompressGzip, CompressBrotli) does
+// not feed its own output back through the pipeline.
+func (p *Package) runTransforms() error {
+	if len(p.transforms) == 0 {
+		return nil
+	}
+
+	paths := make([]string, 0, len(p.Files))
+	for path := range p.Files {
+		paths = append(paths, path)
+	}
+
+	for _, path := range paths {
+		file := p.Files[path]
+
+		fi, err := file.Stat()
+		if err != nil {
+			return err
+		}
+
+		var r io.Reader = file
+		for _, transform := range p.transforms {
+			r, err = transform(path, r)
+			if err != nil {
+				return err
+			}
+		}
+
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		p.Files[path] = newBytesFile(fi.Name(), data, fi.ModTime())
+	}
+
+	return nil
 }
 
 // Write builds the package (via Build) and writes the output the the file
@@ -106,50 +272,271 @@ var (
 	BlockWidth = 12
 )
 
-func reader(input io.Reader, indent int) (string, error) {
-	var (
-		buff      bytes.Buffer
-		err       error
-		curblock  = 0
-		linebreak = "\n" + strings.Repeat("\t", indent)
-	)
+// buildData augments a Package with the content-addressed blob table
+// computed for Build, so the template can reference both the package and
+// the deduplicated byte literals.
+type buildData struct {
+	*Package
+	Blobs   []*blob
+	Dirs    []*dirListing
+	Entries []fileEntry // p.Files, but in a fixed, sorted order for reproducible output
+	Epoch   time.Time
+
+	blobOf map[string]*blob // path -> the blob holding its content
+}
+
+// fileEntry pairs a path with its File for sorted iteration in the
+// template; ranging over p.Files directly would iterate in Go's randomized
+// map order and make every Build diff-noisy even when only one file changed.
+type fileEntry struct {
+	Path string
+	File File
+}
+
+// blob is a single unique, already-encoded run of file bytes. Every path
+// whose content hashes to the same digest shares one blob, so identical
+// files (favicons, empty __init__.py, vendored copies, ...) emit their
+// byte-slice literal only once.
+type blob struct {
+	Var     string // generated top-level variable name
+	Literal string // rendered "0x%02x," byte literal, indented
+}
+
+// entry pairs a File with the blob backing its data and the package's
+// Compression, the arguments the "file" template needs to render a single
+// *asset literal. It exists because a {{ template }} call only passes a
+// single value, and "$" inside the invoked template rebinds to that value
+// rather than the outer root.
+type entry struct {
+	File        File
+	BlobVar     string
+	Compression Compression
+	Epoch       time.Time
+}
+
+func newEntry(d *buildData, path string, file File) entry {
+	return entry{File: file, BlobVar: d.blobOf[path].Var, Compression: d.Compression, Epoch: d.Epoch}
+}
+
+// newBuildData reads every file in p.Files once, groups identical content
+// under a shared blob keyed by its SHA-256 digest, and compresses each
+// unique blob according to p.Compression. Every path is processed in
+// sorted order and every embedded modTime is pinned to a single resolved
+// epoch, so two Build calls over the same inputs produce byte-for-byte
+// identical output.
+func newBuildData(p *Package) (*buildData, error) {
+	epoch, err := resolveSourceDateEpoch(p.SourceDateEpoch)
+	if err != nil {
+		return nil, err
+	}
+
+	byDigest := make(map[string]*blob)
+	blobOf := make(map[string]*blob, len(p.Files))
+
+	paths := make([]string, 0, len(p.Files))
+	for path := range p.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	entries := make([]fileEntry, 0, len(paths))
+	for _, path := range paths {
+		entries = append(entries, fileEntry{Path: path, File: p.Files[path]})
+
+		data, err := io.ReadAll(p.Files[path])
+		if err != nil {
+			return nil, err
+		}
+
+		sum := sha256.Sum256(data)
+		digest := hex.EncodeToString(sum[:])
+
+		b, ok := byDigest[digest]
+		if !ok {
+			encoded, err := compress(data, p.Compression)
+			if err != nil {
+				return nil, err
+			}
+			b = &blob{
+				Var:     "_data" + digest[:12],
+				Literal: encodeBytes(encoded, 1),
+			}
+			byDigest[digest] = b
+		}
+		blobOf[path] = b
+	}
+
+	blobs := make([]*blob, 0, len(byDigest))
+	for _, b := range byDigest {
+		blobs = append(blobs, b)
+	}
+	sort.Slice(blobs, func(i, j int) bool { return blobs[i].Var < blobs[j].Var })
+
+	dirs, err := newDirListings(p, epoch)
+	if err != nil {
+		return nil, err
+	}
+
+	return &buildData{Package: p, Blobs: blobs, Dirs: dirs, Entries: entries, Epoch: epoch, blobOf: blobOf}, nil
+}
+
+// resolveSourceDateEpoch returns epoch if it is set, otherwise the
+// SOURCE_DATE_EPOCH environment variable if present, otherwise the Unix
+// epoch. See Config.SourceDateEpoch.
+func resolveSourceDateEpoch(epoch time.Time) (time.Time, error) {
+	if !epoch.IsZero() {
+		return epoch, nil
+	}
+
+	if v := os.Getenv("SOURCE_DATE_EPOCH"); v != "" {
+		sec, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("resources: invalid SOURCE_DATE_EPOCH %q: %w", v, err)
+		}
+		return time.Unix(sec, 0).UTC(), nil
+	}
+
+	return time.Unix(0, 0).UTC(), nil
+}
+
+// dirListing is one directory's precomputed, already-rendered
+// []os.FileInfo literal of its direct children (files and sub-directories).
+type dirListing struct {
+	Path    string // "." for the root, otherwise the fs.FS-valid directory path
+	Var     string // generated top-level variable name
+	Entries string // rendered composite-literal body, one child per line
+}
 
-	b := make([]byte, BlockWidth)
+func dirVar(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return "_dir" + hex.EncodeToString(sum[:])[:12]
+}
 
-	for n, e := input.Read(b); e == nil; n, e = input.Read(b) {
-		for i := 0; i < n; i++ {
-			_, e = fmt.Fprintf(&buff, "0x%02x,", b[i])
-			if e != nil {
-				err = e
+// newDirListings computes, for every directory implied by p.Files (every
+// path prefix up to and including the root "."), the slice of its direct
+// children. This lets the generated FileSystem.Open resolve a directory
+// lookup with a single map access instead of scanning every file, and lets
+// the resulting FileInfo.files support nested Readdir without rescanning.
+func newDirListings(p *Package, epoch time.Time) ([]*dirListing, error) {
+	children := map[string]map[string]bool{}
+	for name := range p.Files {
+		child := name
+		for {
+			parent := path.Dir(child)
+			if children[parent] == nil {
+				children[parent] = map[string]bool{}
+			}
+			children[parent][child] = true
+			if parent == "." {
 				break
 			}
-			curblock++
-			if curblock < BlockWidth {
-				buff.WriteRune(' ')
+			child = parent
+		}
+	}
+
+	dirs := make([]string, 0, len(children))
+	for d := range children {
+		dirs = append(dirs, d)
+	}
+	sort.Strings(dirs)
+
+	listings := make([]*dirListing, 0, len(dirs))
+	for _, d := range dirs {
+		childPaths := make([]string, 0, len(children[d]))
+		for c := range children[d] {
+			childPaths = append(childPaths, c)
+		}
+		sort.Strings(childPaths)
+
+		var buf bytes.Buffer
+		for _, c := range childPaths {
+			if _, isDir := children[c]; isDir {
+				fmt.Fprintf(&buf, "\t\t&FileInfo{name: %q, mode: os.ModeDir, isDir: true, files: %s},\n", path.Base(c), dirVar(c))
 				continue
 			}
-			buff.WriteString(linebreak)
-			curblock = 0
+
+			fi, err := p.Files[c].Stat()
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(&buf, "\t\t&FileInfo{name: %q, size: %d, modTime: time.Unix(0, %d)},\n",
+				path.Base(c), fi.Size(), epoch.UnixNano())
+		}
+
+		listings = append(listings, &dirListing{
+			Path:    d,
+			Var:     dirVar(d),
+			Entries: buf.String(),
+		})
+	}
+
+	return listings, nil
+}
+
+// compress encodes data according to compression.
+func compress(data []byte, compression Compression) ([]byte, error) {
+	switch compression {
+	case Gzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case Zstd:
+		enc, err := zstd.NewWriter(nil)
+		if err != nil {
+			return nil, err
+		}
+		data = enc.EncodeAll(data, nil)
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+		return data, nil
+	default:
+		return data, nil
+	}
+}
+
+// encodeBytes renders data as a comma-separated list of "0x%02x," byte
+// literals indented for embedding in the generated source.
+func encodeBytes(data []byte, indent int) string {
+	var (
+		buff      bytes.Buffer
+		curblock  = 0
+		linebreak = "\n" + strings.Repeat("\t", indent)
+	)
+
+	for _, b := range data {
+		fmt.Fprintf(&buff, "0x%02x,", b)
+		curblock++
+		if curblock < BlockWidth {
+			buff.WriteRune(' ')
+			continue
 		}
+		buff.WriteString(linebreak)
+		curblock = 0
 	}
 
-	return buff.String(), err
+	return buff.String()
 }
 
 func init() {
-	pkg = template.Must(template.New("file").Funcs(template.FuncMap{"reader": reader}).Parse(fileTemplate))
-	pkg = template.Must(pkg.New("pkg").Parse(pkgTemplate))
+	pkg = template.Must(template.New("file").Parse(fileTemplate))
+	pkg = template.Must(pkg.New("pkg").Funcs(template.FuncMap{"entry": newEntry}).Parse(pkgTemplate))
 }
 
-const fileTemplate = `File{
-				data: []byte{
-					{{ reader . 5 }}
-				},
+const fileTemplate = `&asset{
+				data:        {{ .BlobVar }},
+				compression: {{ .Compression.GoString }},
 				fi: FileInfo{
-					name:    "{{ .Stat.Name }}",
-					size:    {{ .Stat.Size }},
-					modTime: time.Unix(0, {{ .Stat.ModTime.UnixNano }}),
-					isDir:   {{ .Stat.IsDir }},
+					name:    "{{ .File.Stat.Name }}",
+					size:    {{ .File.Stat.Size }},
+					modTime: time.Unix(0, {{ .Epoch.UnixNano }}),
+					isDir:   {{ .File.Stat.IsDir }},
 				},
 			}`
 
@@ -160,71 +547,189 @@ package {{ .Pkg }}
 
 import (
 	"bytes"
+	"compress/gzip"
 	"errors"
-	"net/http"
+	"io"
+	"io/fs"
 	"os"
-	"path/filepath"
+	"path"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/klauspost/compress/zstd"
 )
 
-// FileSystem is an http.FileSystem implementation.
+// Compression identifies how an asset's embedded bytes are encoded. It
+// mirrors the resources.Compression value selected at generation time.
+type Compression int
+
+// Supported compression schemes.
+const (
+	NoCompression Compression = iota
+	Gzip
+	Zstd
+)
+
+// FileSystem is an io/fs.FS (plus fs.ReadDirFS, fs.ReadFileFS, fs.StatFS and
+// fs.GlobFS) implementation backed by the files embedded below. Wrap it with
+// http.FS to obtain an http.FileSystem for use with http.FileServer.
 type FileSystem struct {
-	files map[string]File
+	files map[string]*asset
+	dirs  map[string][]os.FileInfo // directory path -> precomputed direct children
 }
 
 // String returns the content of the file as string.
-func (fs *FileSystem) String(name string) (string, bool) {
-	if filepath.Separator != '/' && strings.IndexRune(name, filepath.Separator) >= 0 ||
-		strings.Contains(name, "\x00") {
+func (fsys *FileSystem) String(name string) (string, bool) {
+	a, ok := fsys.files[clean(name)]
+	if !ok {
 		return "", false
 	}
 
-	file, ok := fs.files[name]
-
-	if !ok {
+	data, err := a.bytes()
+	if err != nil {
 		return "", false
 	}
-
-	return string(file.data), true
+	return string(data), true
 }
 
-// Open implements http.FileSystem.Open
-func (fs *FileSystem) Open(name string) (http.File, error) {
-	if filepath.Separator != '/' && strings.IndexRune(name, filepath.Separator) >= 0 ||
-		strings.Contains(name, "\x00") {
-		return nil, errors.New("http: invalid character in file path")
+// Open implements fs.FS. It rejects NUL bytes and backslashes outright (on
+// every OS, not only where filepath.Separator != '/') and then cleans the
+// remaining path with path.Clean, which resolves ".." segments inside it,
+// and validates it with fs.ValidPath, which rejects any name that still
+// climbs above the root before the map lookup.
+func (fsys *FileSystem) Open(name string) (fs.File, error) {
+	if strings.IndexByte(name, 0) >= 0 || strings.ContainsRune(name, '\\') {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	name = clean(name)
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
 	}
-	file, ok := fs.files[name]
-	if !ok {
-		files := []os.FileInfo{}
-		for path, file := range fs.files {
-			if strings.HasPrefix(path, name) {
-				fi := file.fi
-				files = append(files, &fi)
-			}
-		}
 
-		if len(files) == 0 {
-			return nil, os.ErrNotExist
+	if a, ok := fsys.files[name]; ok {
+		data, err := a.bytes()
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
 		}
+		return &File{Reader: bytes.NewReader(data), fi: a.fi}, nil
+	}
 
-		//We have a directory.
+	if children, ok := fsys.dirs[name]; ok {
 		return &File{
 			fi: FileInfo{
+				name:  path.Base(name),
+				mode:  os.ModeDir,
 				isDir: true,
-				files: files,
+				files: children,
 			}}, nil
 	}
-	file.Reader = bytes.NewReader(file.data)
-	return &file, nil
+
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadFile implements fs.ReadFileFS.
+func (fsys *FileSystem) ReadFile(name string) ([]byte, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (fsys *FileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	dir, ok := file.(fs.ReadDirFile)
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	return dir.ReadDir(-1)
+}
+
+// Stat implements fs.StatFS.
+func (fsys *FileSystem) Stat(name string) (fs.FileInfo, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return file.Stat()
+}
+
+// Glob implements fs.GlobFS.
+func (fsys *FileSystem) Glob(pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	for p := range fsys.files {
+		if ok, _ := path.Match(pattern, p); ok {
+			matches = append(matches, p)
+		}
+	}
+	return matches, nil
+}
+
+// clean normalizes a lookup name to the rooted, slash-separated form used as
+// the map key: no leading slash, "." for the root.
+func clean(name string) string {
+	return path.Clean(strings.TrimPrefix(name, "/"))
+}
+
+// asset is the embedded, possibly-compressed representation of a resource.
+// Its bytes are decompressed at most once per process, on first use.
+type asset struct {
+	data        []byte
+	compression Compression
+	fi          FileInfo
+
+	once    sync.Once
+	decoded []byte
+	err     error
 }
 
-// File implements http.File
+// bytes returns the decompressed content of the asset, decompressing it the
+// first time it is called and caching the result for subsequent callers.
+func (a *asset) bytes() ([]byte, error) {
+	a.once.Do(func() {
+		switch a.compression {
+		case Gzip:
+			r, err := gzip.NewReader(bytes.NewReader(a.data))
+			if err != nil {
+				a.err = err
+				return
+			}
+			defer r.Close()
+			a.decoded, a.err = io.ReadAll(r)
+		case Zstd:
+			d, err := zstd.NewReader(bytes.NewReader(a.data))
+			if err != nil {
+				a.err = err
+				return
+			}
+			defer d.Close()
+			a.decoded, a.err = io.ReadAll(d)
+		default:
+			a.decoded = a.data
+		}
+	})
+	return a.decoded, a.err
+}
+
+// File implements fs.File and fs.ReadDirFile.
 type File struct {
 	*bytes.Reader
-	data []byte
-	fi   FileInfo
+	fi  FileInfo
+	pos int // read position for ReadDir
 }
 
 // Close is a noop-closer.
@@ -232,14 +737,38 @@ func (f *File) Close() error {
 	return nil
 }
 
-// Readdir implements http.File.Readdir
-func (f *File) Readdir(count int) ([]os.FileInfo, error) {
-	return nil, os.ErrNotExist
+// Stat implements fs.File.Stat.
+func (f *File) Stat() (fs.FileInfo, error) {
+	return &f.fi, nil
 }
 
-// Stat implements http.Stat.Readdir
-func (f *File) Stat() (os.FileInfo, error) {
-	return &f.fi, nil
+// ReadDir implements fs.ReadDirFile.
+func (f *File) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !f.fi.isDir {
+		return nil, &fs.PathError{Op: "readdir", Path: f.fi.name, Err: errors.New("not a directory")}
+	}
+
+	rest := f.fi.files[f.pos:]
+	if n <= 0 {
+		f.pos = len(f.fi.files)
+		return direntries(rest), nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	f.pos += n
+	return direntries(rest[:n]), nil
+}
+
+func direntries(infos []os.FileInfo) []fs.DirEntry {
+	entries := make([]fs.DirEntry, len(infos))
+	for i, fi := range infos {
+		entries[i] = fs.FileInfoToDirEntry(fi)
+	}
+	return entries
 }
 
 // FileInfo implements the os.FileInfo interface.
@@ -279,23 +808,38 @@ func (f *FileInfo) IsDir() bool {
 	return f.isDir
 }
 
-// Readdir implements os.FileInfo.Readdir
-func (f *FileInfo) Readdir(count int) ([]os.FileInfo, error) {
-	return f.files, nil
-}
-
 // Sys returns the underlying value.
 func (f *FileInfo) Sys() interface{} {
 	return f.sys
 }
 
+// Content-addressed blobs: files with identical content share one of these
+// instead of each emitting its own byte-slice literal.
+{{ range .Blobs }}var {{ .Var }} = []byte{
+	{{ .Literal }}
+}
+
+{{ end }}
+// Precomputed directory listings: the direct children of every directory,
+// computed once at generation time so Open resolves a directory lookup with
+// a single map access instead of scanning every file.
+{{ range .Dirs }}var {{ .Var }} = []os.FileInfo{
+{{ .Entries }}}
+
+{{ end }}
+var _dirIndex = map[string][]os.FileInfo{
+	{{ range .Dirs }}"{{ .Path }}": {{ .Var }},
+	{{ end }}
+}
+
 {{ if .Declare }}var {{ .Var }} *FileSystem{{ end }}
 
 func init() {
 	{{ .Var }} = &FileSystem{
-		files: map[string]File{
-			{{range $path, $file := .Files }}"/{{ $path }}": {{ template "file" $file }},{{ end }}
+		files: map[string]*asset{
+			{{range .Entries }}"{{ .Path }}": {{ template "file" (entry $ .Path .File) }},{{ end }}
 		},
+		dirs: _dirIndex,
 	}
 }
 `
@@ -0,0 +1,164 @@
+package resources
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildDedupesIdenticalContent(t *testing.T) {
+	p := New()
+	if err := p.Add("a.txt", newBytesFile("a.txt", []byte("same"), time.Time{})); err != nil {
+		t.Fatalf("Add a.txt: %v", err)
+	}
+	if err := p.Add("b.txt", newBytesFile("b.txt", []byte("same"), time.Time{})); err != nil {
+		t.Fatalf("Add b.txt: %v", err)
+	}
+	if err := p.Add("c.txt", newBytesFile("c.txt", []byte("different"), time.Time{})); err != nil {
+		t.Fatalf("Add c.txt: %v", err)
+	}
+
+	var buf strings.Builder
+	if err := p.Build(&buf); err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	out := buf.String()
+
+	if n := strings.Count(out, "var _data"); n != 2 {
+		t.Errorf("got %d unique blob vars, want 2 (one shared by a.txt/b.txt, one for c.txt):\n%s", n, out)
+	}
+}
+
+func TestNewDirListingsChildren(t *testing.T) {
+	p := New()
+	for _, name := range []string{"a.txt", "sub/b.txt", "sub/nested/c.txt"} {
+		if err := p.Add(name, newBytesFile(name, []byte(name), time.Time{})); err != nil {
+			t.Fatalf("Add %s: %v", name, err)
+		}
+	}
+
+	dirs, err := newDirListings(p, time.Unix(0, 0).UTC())
+	if err != nil {
+		t.Fatalf("newDirListings: %v", err)
+	}
+
+	byPath := make(map[string]*dirListing, len(dirs))
+	for _, d := range dirs {
+		byPath[d.Path] = d
+	}
+
+	for _, tt := range []struct {
+		dir      string
+		wantChild string
+	}{
+		{".", "a.txt"},
+		{".", "sub"},
+		{"sub", "b.txt"},
+		{"sub", "nested"},
+		{"sub/nested", "c.txt"},
+	} {
+		d, ok := byPath[tt.dir]
+		if !ok {
+			t.Errorf("missing directory listing for %q", tt.dir)
+			continue
+		}
+		if !strings.Contains(d.Entries, tt.wantChild) {
+			t.Errorf("listing for %q = %q, want it to mention child %q", tt.dir, d.Entries, tt.wantChild)
+		}
+	}
+}
+
+func TestResolveSourceDateEpoch(t *testing.T) {
+	tests := []struct {
+		name    string
+		epoch   time.Time
+		envVal  string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "explicit epoch wins", epoch: time.Unix(100, 0).UTC(), want: time.Unix(100, 0).UTC()},
+		{name: "falls back to SOURCE_DATE_EPOCH", envVal: "1000000000", want: time.Unix(1000000000, 0).UTC()},
+		{name: "falls back to unix epoch when unset", want: time.Unix(0, 0).UTC()},
+		{name: "rejects malformed SOURCE_DATE_EPOCH", envVal: "not-a-number", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.envVal != "" {
+				t.Setenv("SOURCE_DATE_EPOCH", tt.envVal)
+			} else {
+				os.Unsetenv("SOURCE_DATE_EPOCH")
+			}
+
+			got, err := resolveSourceDateEpoch(tt.epoch)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("resolveSourceDateEpoch(%v) = %v, want error", tt.epoch, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveSourceDateEpoch(%v): %v", tt.epoch, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("resolveSourceDateEpoch(%v) = %v, want %v", tt.epoch, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestGeneratedOpenRejectsUnsafePaths locks in the guard clauses that
+// FileSystem.Open emits into the generated package: since that code only
+// exists as rendered text (it has no dependency on this package and cannot
+// be exercised in-process), this pins their presence in Build's output so a
+// future edit can't silently drop the NUL/backslash/".."-traversal checks
+// from request chunk0-7.
+func TestGeneratedOpenRejectsUnsafePaths(t *testing.T) {
+	p := New()
+	if err := p.Add("a.txt", newBytesFile("a.txt", []byte("hi"), time.Time{})); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	var buf strings.Builder
+	if err := p.Build(&buf); err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		`strings.IndexByte(name, 0) >= 0`,
+		`strings.ContainsRune(name, '\\')`,
+		`fs.ValidPath(name)`,
+		`path.Clean(strings.TrimPrefix(name, "/"))`,
+		`fs.ErrNotExist`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("generated output missing expected guard %q", want)
+		}
+	}
+}
+
+func TestDirVarIsStableAndDistinct(t *testing.T) {
+	if dirVar("sub") != dirVar("sub") {
+		t.Errorf("dirVar is not deterministic for the same input")
+	}
+	if dirVar("sub") == dirVar("other") {
+		t.Errorf("dirVar collided for distinct directory names")
+	}
+	if !strings.HasPrefix(dirVar("sub"), "_dir") {
+		t.Errorf("dirVar(%q) = %q, want _dir prefix", "sub", dirVar("sub"))
+	}
+}
+
+func TestResolveSourceDateEpochFormat(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", strconv.Itoa(1700000000))
+	got, err := resolveSourceDateEpoch(time.Time{})
+	if err != nil {
+		t.Fatalf("resolveSourceDateEpoch: %v", err)
+	}
+	if want := time.Unix(1700000000, 0).UTC(); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
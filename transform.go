@@ -0,0 +1,150 @@
+package resources
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/tdewolff/minify/v2"
+	"github.com/tdewolff/minify/v2/css"
+	"github.com/tdewolff/minify/v2/html"
+	"github.com/tdewolff/minify/v2/js"
+)
+
+// ByExtension restricts transform to files whose path has one of exts
+// (matched case-insensitively, each including its leading dot, e.g.
+// ".html"); every other file is passed through unchanged. Use it to scope a
+// Transformer that isn't inherently type-specific, such as CompressGzip or
+// RenderTemplate, to a subset of a package's files before registering it
+// with Package.Use.
+func ByExtension(transform Transformer, exts ...string) Transformer {
+	want := make(map[string]bool, len(exts))
+	for _, ext := range exts {
+		want[strings.ToLower(ext)] = true
+	}
+
+	return func(p string, in io.Reader) (io.Reader, error) {
+		if !want[strings.ToLower(path.Ext(p))] {
+			return in, nil
+		}
+		return transform(p, in)
+	}
+}
+
+// mimeExtensions maps the media types Minify knows about to the file
+// extensions they apply to, so e.g. Minify("text/css") only runs over
+// .css files and leaves everything else, including other text assets,
+// untouched.
+var mimeExtensions = map[string][]string{
+	"text/html":              {".html", ".htm"},
+	"text/css":               {".css"},
+	"application/javascript": {".js"},
+}
+
+// Minify returns a Transformer that minifies files whose extension matches
+// mediaType ("text/html", "text/css" or "application/javascript") through a
+// minifier registered for that type; files of any other type pass through
+// unchanged.
+func Minify(mediaType string) Transformer {
+	m := minify.New()
+	m.AddFunc("text/html", html.Minify)
+	m.AddFunc("text/css", css.Minify)
+	m.AddFunc("application/javascript", js.Minify)
+
+	minify := func(path string, in io.Reader) (io.Reader, error) {
+		var buf bytes.Buffer
+		if err := m.Minify(mediaType, &buf, in); err != nil {
+			return nil, err
+		}
+		return &buf, nil
+	}
+
+	return ByExtension(minify, mimeExtensions[mediaType]...)
+}
+
+// CompressGzip returns a Transformer that leaves a file's bytes untouched
+// and additionally adds a "<path>.gz" entry to p holding its gzip-compressed
+// content, so an HTTP handler can serve it directly with
+// Content-Encoding: gzip instead of compressing on every request. It applies
+// to every file it is run over; wrap it with ByExtension to restrict it to
+// specific extensions.
+func CompressGzip(p *Package) Transformer {
+	return func(path string, in io.Reader) (io.Reader, error) {
+		data, err := io.ReadAll(in)
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+
+		if err := p.Add(path+".gz", newBytesFile(path+".gz", buf.Bytes(), time.Time{})); err != nil {
+			return nil, err
+		}
+
+		return bytes.NewReader(data), nil
+	}
+}
+
+// CompressBrotli mirrors CompressGzip, adding a "<path>.br" entry holding
+// the file's brotli-compressed content. Like CompressGzip, it applies to
+// every file it is run over; wrap it with ByExtension to restrict it.
+func CompressBrotli(p *Package) Transformer {
+	return func(path string, in io.Reader) (io.Reader, error) {
+		data, err := io.ReadAll(in)
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		w := brotli.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+
+		if err := p.Add(path+".br", newBytesFile(path+".br", buf.Bytes(), time.Time{})); err != nil {
+			return nil, err
+		}
+
+		return bytes.NewReader(data), nil
+	}
+}
+
+// RenderTemplate returns a Transformer that parses a file's content as a
+// text/template and executes it against data before the result is
+// embedded. Use it to pre-render front-end templates at generation time
+// instead of at request time. It applies to every file it is run over;
+// wrap it with ByExtension to restrict it to template sources.
+func RenderTemplate(data interface{}) Transformer {
+	return func(path string, in io.Reader) (io.Reader, error) {
+		src, err := io.ReadAll(in)
+		if err != nil {
+			return nil, err
+		}
+
+		tmpl, err := template.New(path).Parse(string(src))
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+		return &buf, nil
+	}
+}